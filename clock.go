@@ -0,0 +1,44 @@
+package retry
+
+import "time"
+
+// Timer models the part of *time.Timer that RunContext needs, so that tests
+// can swap in a fake one instead of waiting on the real clock.
+type Timer interface {
+	// C returns the channel on which the time is sent when the timer fires.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing, returning false if it already
+	// fired or was already stopped.
+	Stop() bool
+}
+
+// Clock is the source of time RunContext uses to schedule retries. The zero
+// value of Retrier uses realClock, which is backed by the real time package;
+// tests can install a fake one with WithClock.
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// realClock is the default Clock, backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time { return time.Now() }
+
+func (realClock) NewTimer(d time.Duration) Timer { return realTimer{time.NewTimer(d)} }
+
+// realTimer adapts *time.Timer to the Timer interface.
+type realTimer struct {
+	t *time.Timer
+}
+
+func (r realTimer) C() <-chan time.Time { return r.t.C }
+func (r realTimer) Stop() bool          { return r.t.Stop() }
+
+// WithClock installs the Clock RunContext uses to tell time and schedule
+// retries, in place of the real clock. It returns r so calls can be chained
+// onto NewRetrier/NewRetrierWithStrategy.
+func (r *Retrier) WithClock(c Clock) *Retrier {
+	r.clock = c
+	return r
+}
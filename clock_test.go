@@ -0,0 +1,57 @@
+package retry
+
+import (
+	"sync"
+	"time"
+)
+
+// fakeClock is a Clock whose timers fire immediately instead of waiting on
+// the real clock, while still recording the virtual time that would have
+// elapsed. This lets tests assert on backoff schedules without racy
+// time.Sleeps.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	fireAt := f.now
+	f.mu.Unlock()
+
+	ch := make(chan time.Time, 1)
+	ch <- fireAt
+	return fakeTimer{ch: ch}
+}
+
+type fakeTimer struct {
+	ch chan time.Time
+}
+
+func (f fakeTimer) C() <-chan time.Time { return f.ch }
+func (f fakeTimer) Stop() bool          { return true }
+
+// blockingClock's timers never fire. It's used to test context cancellation
+// deterministically: with a timer that never sends, RunContext's select can
+// only resolve via ctx.Done(), with no reliance on real wall-clock timing.
+type blockingClock struct{}
+
+func (blockingClock) Now() time.Time { return time.Unix(0, 0) }
+
+func (blockingClock) NewTimer(time.Duration) Timer { return blockingTimer{} }
+
+type blockingTimer struct{}
+
+func (blockingTimer) C() <-chan time.Time { return nil }
+func (blockingTimer) Stop() bool          { return true }
@@ -0,0 +1,164 @@
+// Package httpretry provides an http.RoundTripper built on top of
+// retry.Retrier, turning the copy-pasted "retry this HTTP call" snippet
+// from ExampleRetrier_Run into a supported API.
+package httpretry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/flowchartsman/retry"
+)
+
+// DefaultRetryStatusCodes are the response status codes RoundTripper
+// retries when RetryStatusCodes is nil.
+var DefaultRetryStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// RoundTripper retries requests against connection errors and configurable
+// retryable status codes. Its zero value is ready to use.
+type RoundTripper struct {
+	// Next is the underlying RoundTripper. http.DefaultTransport is used if
+	// nil.
+	Next http.RoundTripper
+	// Retrier drives the retry schedule. retry.NewRetrier(0, 0, 0) (the
+	// package defaults) is used if nil.
+	Retrier *retry.Retrier
+	// RetryStatusCodes are the response status codes that should be
+	// retried. DefaultRetryStatusCodes is used if nil.
+	RetryStatusCodes map[int]bool
+	// RetryPost opts POST requests into retrying, but only when their body
+	// is rewindable (req.GetBody != nil). POST is not retried by default,
+	// since it isn't idempotent.
+	RetryPost bool
+}
+
+// Do sends req using a RoundTripper with default settings, retrying
+// transient failures. ctx governs the whole retry loop, not just a single
+// attempt.
+func Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	return (&RoundTripper{}).RoundTrip(req.WithContext(ctx))
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := rt.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	retryStatusCodes := rt.RetryStatusCodes
+	if retryStatusCodes == nil {
+		retryStatusCodes = DefaultRetryStatusCodes
+	}
+	if !rt.canRetry(req) {
+		return next.RoundTrip(req)
+	}
+	r := rt.Retrier
+	if r == nil {
+		r = retry.NewRetrier(0, 0, 0)
+	}
+
+	var resp *http.Response
+	err := r.RunContext(req.Context(), func(ctx context.Context) error {
+		attempt := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return retry.Stop(err)
+			}
+			attempt.Body = body
+		}
+
+		res, err := next.RoundTrip(attempt)
+		if err != nil {
+			return err
+		}
+		if !retryStatusCodes[res.StatusCode] {
+			resp = res
+			return nil
+		}
+
+		// This response may still be the one RoundTrip returns, if retries
+		// end up exhausted, so buffer its body instead of draining it: that
+		// lets us close the real connection (so it can be reused) while
+		// keeping the body readable for the caller.
+		buffered, err := bufferResponse(res)
+		if err != nil {
+			return err
+		}
+		retryAfter, hasRetryAfter := retry.ParseRetryAfter(buffered)
+		statusErr := &retryableStatusError{resp: buffered}
+		if hasRetryAfter {
+			return retry.RetryAfter(statusErr, retryAfter)
+		}
+		return statusErr
+	})
+	if err != nil {
+		// A retryable status code isn't a transport failure: per the
+		// http.RoundTripper contract, it comes back as (resp, nil) with the
+		// last response intact, not as an error.
+		var statusErr *retryableStatusError
+		if errors.As(err, &statusErr) {
+			return statusErr.resp, nil
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// retryableStatusError carries the last response RoundTrip saw for a
+// retryable status code, so it can be returned to the caller once retries
+// are exhausted instead of being discarded in favor of a synthetic error.
+type retryableStatusError struct {
+	resp *http.Response
+}
+
+// Error implements error.
+func (e *retryableStatusError) Error() string {
+	return fmt.Sprintf("httpretry: retryable status %s", e.resp.Status)
+}
+
+// bufferResponse reads res's body into memory and closes the original, so
+// the underlying connection can be recycled for a retry while the body
+// stays readable even after a later attempt reuses that connection.
+func bufferResponse(res *http.Response) (*http.Response, error) {
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = io.NopCloser(bytes.NewReader(body))
+	return res, nil
+}
+
+// canRetry reports whether req is safe to retry. GET/HEAD/OPTIONS/TRACE are
+// idempotent, but like PUT/DELETE they aren't always bodyless (search-style
+// APIs send a GET with a body), and like POST they must not be retried if
+// they carry a body we can't rewind: req.Clone only shallow-copies Body, so
+// retrying would silently resend a drained (and therefore empty or
+// truncated) body unless req.GetBody can rebuild it.
+func (rt *RoundTripper) canRetry(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace,
+		http.MethodPut, http.MethodDelete:
+		return !hasBody(req) || req.GetBody != nil
+	case http.MethodPost:
+		return rt.RetryPost && req.GetBody != nil
+	default:
+		return false
+	}
+}
+
+// hasBody reports whether req carries a request body that would need to be
+// rewound for a retry.
+func hasBody(req *http.Request) bool {
+	return req.Body != nil && req.Body != http.NoBody
+}
@@ -0,0 +1,250 @@
+package httpretry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flowchartsman/retry"
+)
+
+func fastRetrier() *retry.Retrier {
+	return retry.NewRetrier(3, time.Millisecond, time.Millisecond)
+}
+
+func TestRoundTripRetriesRetryableStatus(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	rt := &RoundTripper{Retrier: fastRetrier()}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+	if hits != 3 {
+		t.Errorf("expected 3 hits, got %d", hits)
+	}
+}
+
+func TestRoundTripGivesUpAfterMaxTries(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("upstream unavailable"))
+	}))
+	defer srv.Close()
+
+	rt := &RoundTripper{Retrier: fastRetrier()}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("expected the last response, not an error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected 502, got %d", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "upstream unavailable" {
+		t.Errorf("expected the last response body to be preserved, got %q", body)
+	}
+	if hits != 3 {
+		t.Errorf("expected 3 hits, got %d", hits)
+	}
+}
+
+func TestRoundTripDoesNotRetryPostByDefault(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	rt := &RoundTripper{Retrier: fastRetrier()}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("body"))
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if hits != 1 {
+		t.Errorf("expected exactly 1 hit for a non-retried POST, got %d", hits)
+	}
+}
+
+func TestRoundTripDoesNotRetryGetWithUnrewindableBody(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// A search-style GET carrying a body with no GetBody isn't rewindable,
+	// even though GET is normally assumed to be bodyless.
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, io.NopCloser(strings.NewReader("query")))
+	req.GetBody = nil
+
+	rt := &RoundTripper{Retrier: fastRetrier()}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if hits != 1 {
+		t.Errorf("expected exactly 1 hit for an unrewindable GET, got %d", hits)
+	}
+}
+
+func TestRoundTripDoesNotRetryPutWithUnrewindableBody(t *testing.T) {
+	var hits int32
+	var gotBodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBodies = append(gotBodies, string(body))
+		atomic.AddInt32(&hits, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// A plain io.Reader (not *bytes.Reader/*bytes.Buffer/*strings.Reader)
+	// gives NewRequest no way to set GetBody, so this body isn't rewindable.
+	req, _ := http.NewRequest(http.MethodPut, srv.URL, io.NopCloser(strings.NewReader("payload")))
+	req.GetBody = nil
+
+	rt := &RoundTripper{Retrier: fastRetrier()}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if hits != 1 {
+		t.Errorf("expected exactly 1 hit for an unrewindable PUT, got %d", hits)
+	}
+	if len(gotBodies) != 1 || gotBodies[0] != "payload" {
+		t.Errorf("expected the original body to be sent once, got %v", gotBodies)
+	}
+}
+
+func TestRoundTripRetriesPutWithRewindableBody(t *testing.T) {
+	var hits int32
+	var gotBodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotBodies = append(gotBodies, string(body))
+		if atomic.AddInt32(&hits, 1) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL, bytes.NewReader([]byte("payload")))
+	resp, err := (&RoundTripper{Retrier: fastRetrier()}).RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if hits != 2 {
+		t.Errorf("expected 2 hits, got %d", hits)
+	}
+	for i, b := range gotBodies {
+		if b != "payload" {
+			t.Errorf("attempt %d: expected body %q, got %q", i+1, "payload", b)
+		}
+	}
+}
+
+func TestRoundTripRetriesPostWhenOptedInAndRewindable(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("expected body %q on every attempt, got %q", "payload", body)
+		}
+		if atomic.AddInt32(&hits, 1) < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, bytes.NewReader([]byte("payload")))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader([]byte("payload"))), nil
+	}
+
+	rt := &RoundTripper{Retrier: fastRetrier(), RetryPost: true}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if hits != 2 {
+		t.Errorf("expected 2 hits, got %d", hits)
+	}
+}
+
+func TestRoundTripHonorsRetryAfter(t *testing.T) {
+	var hits int32
+	start := time.Now()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	rt := &RoundTripper{Retrier: retry.NewRetrier(3, time.Millisecond, time.Millisecond)}
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("expected Retry-After to be honored, only waited %v", elapsed)
+	}
+}
+
+func TestDoUsesDefaultRoundTripperAndContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	resp, err := Do(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+}
@@ -2,8 +2,7 @@ package retry
 
 import (
 	"context"
-	"math"
-	"math/rand"
+	"errors"
 	"time"
 )
 
@@ -14,28 +13,110 @@ const (
 	DefaultMaxDelay     = time.Millisecond * 1000
 )
 
-// Retrier retries code blocks with or without context using an exponential
-// backoff algorithm with jitter. It is intended to be used as a retry policy,
-// which means it is safe to create and use concurrently.
+// Retrier retries code blocks with or without context using a pluggable
+// backoff Strategy (exponential backoff with jitter by default). It is
+// intended to be used as a retry policy, which means it is safe to create
+// and use concurrently, as long as its Strategy is (see the Strategy
+// implementation's docs for any caveats, including WithRandSource's).
 type Retrier struct {
-	maxTries     int
-	initialDelay time.Duration
-	maxDelay     time.Duration
+	maxTries          int
+	strategy          Strategy
+	isRetryable       IsRetryable
+	clock             Clock
+	maxElapsed        time.Duration
+	perAttemptTimeout time.Duration
+	onRetry           func(attempt int, err error, nextDelay time.Duration)
+	onGiveUp          func(attempts int, err error)
 }
 
-// NewRetrier returns a retrier for retrying functions with expoential backoff.
-// If any of the values are <= 0, they will be set to their respective defaults.
+// IsRetryable classifies an error returned by the retried function as
+// retryable (true) or terminal (false). It is a lower-ceremony alternative
+// to wrapping every non-retryable error site in Stop: register one with
+// WithRetryable and RunContext will consult it on every failure.
+type IsRetryable func(error) bool
+
+// Retryable can be implemented by an error to tell RunContext whether it
+// should be retried, without requiring the caller to register an
+// IsRetryable classifier. If Retryable() returns false, the error is
+// treated like one returned by Stop.
+type Retryable interface {
+	Retryable() bool
+}
+
+// WithRetryable registers fn as the classifier RunContext uses to decide
+// whether an error is retryable. It returns r so calls can be chained onto
+// NewRetrier/NewRetrierWithStrategy.
+func (r *Retrier) WithRetryable(fn IsRetryable) *Retrier {
+	r.isRetryable = fn
+	return r
+}
+
+// WithMaxElapsed bounds the cumulative wall time RunContext will spend
+// retrying, measured from the first attempt: once it's exceeded, RunContext
+// returns the last error instead of waiting for another attempt. A value of
+// 0 (the default) means no such bound is enforced. It returns r so calls can
+// be chained onto NewRetrier/NewRetrierWithStrategy.
+func (r *Retrier) WithMaxElapsed(d time.Duration) *Retrier {
+	r.maxElapsed = d
+	return r
+}
+
+// WithPerAttemptTimeout wraps every invocation of funcToRetry in a
+// context.WithTimeout derived from the caller's context, so a single slow
+// attempt can't stall the whole retry budget. A value of 0 (the default)
+// leaves the caller's context as-is. It returns r so calls can be chained
+// onto NewRetrier/NewRetrierWithStrategy.
+func (r *Retrier) WithPerAttemptTimeout(d time.Duration) *Retrier {
+	r.perAttemptTimeout = d
+	return r
+}
+
+// WithOnRetry registers fn to be called after every failed attempt, just
+// before RunContext sleeps for nextDelay, so callers can drive structured
+// logs or metrics without re-deriving the delay themselves. attempt is the
+// number of attempts made so far. It returns r so calls can be chained onto
+// NewRetrier/NewRetrierWithStrategy.
+func (r *Retrier) WithOnRetry(fn func(attempt int, err error, nextDelay time.Duration)) *Retrier {
+	r.onRetry = fn
+	return r
+}
+
+// WithOnGiveUp registers fn to be called when RunContext gives up and
+// returns a non-nil error, whether because it ran out of tries, ran out of
+// its maxElapsed budget, hit a terminal error, or its context was canceled.
+// It returns r so calls can be chained onto NewRetrier/NewRetrierWithStrategy.
+func (r *Retrier) WithOnGiveUp(fn func(attempts int, err error)) *Retrier {
+	r.onGiveUp = fn
+	return r
+}
+
+// giveUp runs the OnGiveUp hook, if any, and returns err so call sites can
+// write `return r.giveUp(attempts, err)`.
+func (r *Retrier) giveUp(attempts int, err error) error {
+	if r.onGiveUp != nil {
+		r.onGiveUp(attempts, err)
+	}
+	return err
+}
+
+// NewRetrier returns a retrier for retrying functions with exponential
+// backoff. If any of the values are <= 0, they will be set to their
+// respective defaults.
 func NewRetrier(maxTries int, initialDelay, maxDelay time.Duration) *Retrier {
 	if maxTries <= 0 {
 		maxTries = DefaultMaxTries
 	}
-	if initialDelay <= 0 {
-		initialDelay = DefaultInitialDelay
-	}
-	if maxDelay <= 0 {
-		maxDelay = DefaultMaxDelay
+	return &Retrier{maxTries: maxTries, strategy: NewExponentialBackoff(initialDelay, maxDelay)}
+}
+
+// NewRetrierWithStrategy returns a retrier for retrying functions whose
+// backoff is computed by s. If maxTries is <= 0, it is set to
+// DefaultMaxTries.
+func NewRetrierWithStrategy(maxTries int, s Strategy) *Retrier {
+	if maxTries <= 0 {
+		maxTries = DefaultMaxTries
 	}
-	return &Retrier{maxTries, initialDelay, maxDelay}
+	return &Retrier{maxTries: maxTries, strategy: s}
 }
 
 // Run runs a function until it returns nil, until it returns a terminal error,
@@ -55,22 +136,31 @@ func (r *Retrier) Run(funcToRetry func() error) error {
 // simply stop the retry loop when the function returns if the context is done.
 func (r *Retrier) RunContext(ctx context.Context, funcToRetry func(context.Context) error) error {
 	maxTries := r.maxTries
-	initialDelay := r.initialDelay
-	maxDelay := r.maxDelay
 	if maxTries <= 0 {
 		maxTries = DefaultMaxTries
 	}
-	if initialDelay <= 0 {
-		initialDelay = DefaultInitialDelay
+	strategy := r.strategy
+	if strategy == nil {
+		strategy = NewExponentialBackoff(DefaultInitialDelay, DefaultMaxDelay)
 	}
-	if maxDelay <= 0 {
-		maxDelay = DefaultMaxDelay
+	strategy.Reset()
+	clock := r.clock
+	if clock == nil {
+		clock = realClock{}
 	}
-	randSource := rand.New(rand.NewSource(time.Now().UnixNano()))
+	start := clock.Now()
 	attempts := 0
 	for {
+		attemptCtx := ctx
+		var cancelAttempt context.CancelFunc
+		if r.perAttemptTimeout > 0 {
+			attemptCtx, cancelAttempt = context.WithTimeout(ctx, r.perAttemptTimeout)
+		}
 		// Attempt to run the function
-		err := funcToRetry(ctx)
+		err := funcToRetry(attemptCtx)
+		if cancelAttempt != nil {
+			cancelAttempt()
+		}
 		// If there's no error, we're done!
 		if err == nil {
 			return nil
@@ -79,22 +169,43 @@ func (r *Retrier) RunContext(ctx context.Context, funcToRetry func(context.Conte
 		attempts++
 		// If we've just run our last attempt, return the error we got
 		if attempts == maxTries {
-			return err
+			return r.giveUp(attempts, err)
+		}
+		// If we've blown our elapsed-time budget, return the error we got
+		if r.maxElapsed > 0 && clock.Now().Sub(start) >= r.maxElapsed {
+			return r.giveUp(attempts, err)
 		}
 
 		// Check if the error is a terminal error. If so, stop!
 		switch v := err.(type) {
 		case terminalError:
-			return v.e
+			return r.giveUp(attempts, v.e)
+		}
+		// Give the registered classifier, and then the error itself, a
+		// chance to say this isn't worth retrying.
+		if r.isRetryable != nil && !r.isRetryable(err) {
+			return r.giveUp(attempts, err)
+		}
+		if v, ok := err.(Retryable); ok && !v.Retryable() {
+			return r.giveUp(attempts, err)
 		}
 		// Otherwise wait for the next duration or until the context is done,
-		// whichever comes first
+		// whichever comes first. A RetryAfter error overrides the computed
+		// backoff whenever it asks for longer.
+		nextDelay := strategy.NextDelay(attempts)
+		var raErr retryAfterError
+		if errors.As(err, &raErr) && raErr.delay > nextDelay {
+			nextDelay = raErr.delay
+		}
+		if r.onRetry != nil {
+			r.onRetry(attempts, err, nextDelay)
+		}
 		select {
-		case <-time.NewTimer(getnextBackoff(attempts, initialDelay, maxDelay, randSource)).C:
+		case <-clock.NewTimer(nextDelay).C():
 			// duration elapsed, loop
 		case <-ctx.Done():
 			// context cancelled, return the last error we got
-			return err
+			return r.giveUp(attempts, err)
 		}
 	}
 }
@@ -114,23 +225,3 @@ type terminalError struct {
 func (t terminalError) Error() string {
 	return t.e.Error()
 }
-
-func getnextBackoff(attempts int, initialDelay, maxDelay time.Duration, randSource *rand.Rand) time.Duration {
-	var backoff time.Duration
-
-	// this complexity is to limit the backoff to values that fit into signed 64 bit numbers
-	attemptsLimit := int(math.Log2(float64(initialDelay))) + 1
-	if attemptsLimit < 63-attempts {
-		backoff = time.Duration(1<<uint64(attempts)) * jitterDuration(initialDelay, randSource)
-		if backoff > maxDelay {
-			backoff = jitterDuration(maxDelay/2, randSource)
-		}
-	} else {
-		backoff = jitterDuration(maxDelay/2, randSource)
-	}
-	return backoff + initialDelay
-}
-
-func jitterDuration(duration time.Duration, randSource *rand.Rand) time.Duration {
-	return time.Duration(randSource.Int63n(int64(duration)) + int64(duration))
-}
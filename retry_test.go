@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"math/rand"
 	"net/http"
 	"reflect"
 	"sync"
@@ -17,12 +16,11 @@ var errTest = errors.New("test error")
 func TestBackoffBacksOff(t *testing.T) {
 	t.Run("r.Run", func(t *testing.T) {
 		tries := 0
-		start := time.Now()
-		var last time.Time
-		retrier := NewRetrier(5, 50*time.Millisecond, 50*time.Millisecond)
+		clock := newFakeClock()
+		start := clock.Now()
+		retrier := NewRetrier(5, 50*time.Millisecond, 50*time.Millisecond).WithClock(clock)
 		err := retrier.Run(func() error {
 			tries++
-			last = time.Now()
 			return errTest
 		})
 
@@ -34,18 +32,17 @@ func TestBackoffBacksOff(t *testing.T) {
 		}
 
 		max := 5 * (50 + 50) * time.Millisecond
-		if last.Sub(start) > max {
-			t.Errorf("should have taken less than %v, took %d", max, last.Sub(start).Nanoseconds()/1000000)
+		if elapsed := clock.Now().Sub(start); elapsed > max {
+			t.Errorf("should have taken less than %v, took %v", max, elapsed)
 		}
 	})
 	t.Run("r.RunContext", func(t *testing.T) {
 		tries := 0
-		start := time.Now()
-		var last time.Time
-		retrier := NewRetrier(5, 50*time.Millisecond, 50*time.Millisecond)
+		clock := newFakeClock()
+		start := clock.Now()
+		retrier := NewRetrier(5, 50*time.Millisecond, 50*time.Millisecond).WithClock(clock)
 		err := retrier.RunContext(context.Background(), func(ctx context.Context) error {
 			tries++
-			last = time.Now()
 			return errTest
 		})
 
@@ -56,8 +53,8 @@ func TestBackoffBacksOff(t *testing.T) {
 			t.Errorf("err should equal errTest, got: %v", err)
 		}
 		max := 5 * (50 + 50) * time.Millisecond
-		if last.Sub(start) > max {
-			t.Errorf("should have taken less than %v, took %d", max, last.Sub(start).Nanoseconds()/1000000)
+		if elapsed := clock.Now().Sub(start); elapsed > max {
+			t.Errorf("should have taken less than %v, took %v", max, elapsed)
 		}
 	})
 }
@@ -103,18 +100,25 @@ func TestRunContextExitsEarlyWhenContextCanceled(t *testing.T) {
 	var err error
 	var wg sync.WaitGroup
 	tries := 0
+	started := make(chan struct{}, 1)
 	ctx, canceler := context.WithCancel(context.Background())
-	retrier := NewRetrier(5, 50*time.Millisecond, 50*time.Millisecond)
+	// blockingClock's timer never fires, so RunContext can only return by
+	// way of ctx.Done(), making this deterministic with no real sleeping.
+	retrier := NewRetrier(5, 50*time.Millisecond, 50*time.Millisecond).WithClock(blockingClock{})
 
 	wg.Add(1)
 	go func() {
 		err = retrier.RunContext(ctx, func(ctx context.Context) error {
 			tries++
+			select {
+			case started <- struct{}{}:
+			default:
+			}
 			return errTest
 		})
 		wg.Done()
 	}()
-	time.Sleep(200 * time.Millisecond)
+	<-started
 	canceler()
 	wg.Wait()
 
@@ -167,11 +171,156 @@ func TestRetrierGetsDefaultsIfLessThanZero(t *testing.T) {
 	if r.maxTries != DefaultMaxTries {
 		t.Errorf("expected maxTries to be %d, got %d", DefaultMaxTries, r.maxTries)
 	}
-	if r.initialDelay != DefaultInitialDelay {
-		t.Errorf("expected initialDelay to be %d, got %d", DefaultInitialDelay, r.initialDelay)
+	strategy, ok := r.strategy.(*ExponentialBackoff)
+	if !ok {
+		t.Fatalf("expected strategy to be *ExponentialBackoff, got %T", r.strategy)
+	}
+	if strategy.InitialDelay != DefaultInitialDelay {
+		t.Errorf("expected InitialDelay to be %d, got %d", DefaultInitialDelay, strategy.InitialDelay)
+	}
+	if strategy.MaxDelay != DefaultMaxDelay {
+		t.Errorf("expected MaxDelay to be %d, got %d", DefaultMaxDelay, strategy.MaxDelay)
+	}
+}
+
+func TestWithMaxElapsedStopsOnceBudgetIsExceeded(t *testing.T) {
+	tries := 0
+	clock := newFakeClock()
+	retrier := NewRetrier(100, 50*time.Millisecond, 50*time.Millisecond).
+		WithClock(clock).
+		WithMaxElapsed(120 * time.Millisecond)
+	err := retrier.Run(func() error {
+		tries++
+		return errTest
+	})
+	if err != errTest {
+		t.Errorf("err should equal errTest, got: %v", err)
+	}
+	if tries >= 100 {
+		t.Error("reached maxTries, but should have stopped on maxElapsed first")
+	}
+	if tries < 2 {
+		t.Errorf("expected at least 2 tries before the elapsed budget was exceeded, got %d", tries)
+	}
+}
+
+func TestWithPerAttemptTimeoutCancelsSlowAttempts(t *testing.T) {
+	tries := 0
+	var sawDeadlines int
+	retrier := NewRetrier(3, time.Millisecond, time.Millisecond).
+		WithPerAttemptTimeout(10 * time.Millisecond)
+	err := retrier.RunContext(context.Background(), func(ctx context.Context) error {
+		tries++
+		<-ctx.Done()
+		if ctx.Err() == context.DeadlineExceeded {
+			sawDeadlines++
+		}
+		return ctx.Err()
+	})
+	_ = err
+	if tries != 3 {
+		t.Errorf("expected 3 tries, got %d", tries)
+	}
+	if sawDeadlines != 3 {
+		t.Errorf("expected every attempt's context to have hit its deadline, got %d", sawDeadlines)
+	}
+
+	retrier = NewRetrier(3, time.Millisecond, time.Millisecond).
+		WithPerAttemptTimeout(5 * time.Millisecond)
+	var sawDeadline bool
+	err = retrier.RunContext(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		sawDeadline = ctx.Err() == context.DeadlineExceeded
+		return ctx.Err()
+	})
+	if !sawDeadline {
+		t.Error("expected the attempt's context to have hit its deadline")
+	}
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestOnRetryIsCalledForEveryFailedAttempt(t *testing.T) {
+	var gotAttempts []int
+	tries := 0
+	retrier := NewRetrier(3, time.Millisecond, time.Millisecond).
+		WithOnRetry(func(attempt int, err error, nextDelay time.Duration) {
+			gotAttempts = append(gotAttempts, attempt)
+			if err != errTest {
+				t.Errorf("expected errTest, got %v", err)
+			}
+		})
+	_ = retrier.Run(func() error {
+		tries++
+		return errTest
+	})
+	if want := []int{1, 2}; !reflect.DeepEqual(gotAttempts, want) {
+		t.Errorf("expected OnRetry attempts %v, got %v", want, gotAttempts)
+	}
+}
+
+func TestOnGiveUpIsCalledOnceWhenTheLoopExits(t *testing.T) {
+	var gotAttempts int
+	var gotErr error
+	calls := 0
+	retrier := NewRetrier(3, time.Millisecond, time.Millisecond).
+		WithOnGiveUp(func(attempts int, err error) {
+			calls++
+			gotAttempts = attempts
+			gotErr = err
+		})
+	err := retrier.Run(func() error {
+		return errTest
+	})
+	if calls != 1 {
+		t.Errorf("expected OnGiveUp to be called once, got %d", calls)
+	}
+	if gotAttempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", gotAttempts)
 	}
-	if r.maxDelay != DefaultMaxDelay {
-		t.Errorf("expected maxDelay to be %d, got %d", DefaultMaxDelay, r.maxDelay)
+	if gotErr != errTest || err != errTest {
+		t.Errorf("expected errTest, got OnGiveUp=%v Run=%v", gotErr, err)
+	}
+}
+
+func TestWithRetryableStopsWhenClassifierSaysNo(t *testing.T) {
+	tries := 0
+	retrier := NewRetrier(5, time.Millisecond, time.Millisecond).
+		WithRetryable(func(err error) bool {
+			return err != errTest
+		})
+	err := retrier.Run(func() error {
+		tries++
+		return errTest
+	})
+	if tries != 1 {
+		t.Errorf("expected 1 try, got %d", tries)
+	}
+	if err != errTest {
+		t.Errorf("err should equal errTest, got: %v", err)
+	}
+}
+
+type retryableError struct {
+	retryable bool
+}
+
+func (r retryableError) Error() string   { return "retryable error" }
+func (r retryableError) Retryable() bool { return r.retryable }
+
+func TestRetryableInterfaceStopsTheLoop(t *testing.T) {
+	tries := 0
+	retrier := NewRetrier(5, time.Millisecond, time.Millisecond)
+	err := retrier.Run(func() error {
+		tries++
+		return retryableError{retryable: false}
+	})
+	if tries != 1 {
+		t.Errorf("expected 1 try, got %d", tries)
+	}
+	if _, ok := err.(retryableError); !ok {
+		t.Errorf("expected retryableError, got %T: %v", err, err)
 	}
 }
 
@@ -251,12 +400,9 @@ func TestBackoffPanicFix(t *testing.T) {
 		}
 	}()
 
-	initialDelay := 500 * time.Millisecond
-	maxDelay := 1 * time.Millisecond
-	randSource := rand.New(rand.NewSource(time.Now().UnixNano()))
-
+	strategy := NewExponentialBackoff(500*time.Millisecond, 1*time.Millisecond)
 	for attempts := 0; attempts < 100; attempts++ {
-		_ = getnextBackoff(attempts, initialDelay, maxDelay, randSource)
+		_ = strategy.NextDelay(attempts)
 	}
 }
 
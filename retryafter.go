@@ -0,0 +1,59 @@
+package retry
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryAfter wraps err with a minimum delay that RunContext will wait before
+// the next attempt, overriding the computed backoff whenever it is longer
+// (the actual sleep is max(computedBackoff, d)). It's meant for servers that
+// tell you exactly how long to back off, such as the HTTP Retry-After
+// header: see ParseRetryAfter.
+func RetryAfter(err error, d time.Duration) error {
+	return retryAfterError{err: err, delay: d}
+}
+
+// retryAfterError carries a minimum retry delay alongside the error that
+// triggered it.
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+// Error implements error.
+func (r retryAfterError) Error() string {
+	return r.err.Error()
+}
+
+// Unwrap allows errors.Is/errors.As to see through to the wrapped error.
+func (r retryAfterError) Unwrap() error {
+	return r.err
+}
+
+// ParseRetryAfter parses the Retry-After header on resp, handling both the
+// delta-seconds form ("120") and the HTTP-date form
+// ("Fri, 31 Dec 1999 23:59:59 GMT") described in RFC 7231 Section 7.1.3. ok
+// is false if resp has no Retry-After header, or its value couldn't be
+// parsed as a non-negative delay.
+func ParseRetryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := time.Until(t); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
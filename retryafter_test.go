@@ -0,0 +1,105 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryAfterOverridesComputedBackoff(t *testing.T) {
+	tries := 0
+	start := time.Now()
+	retrier := NewRetrier(3, time.Millisecond, time.Millisecond)
+	err := retrier.Run(func() error {
+		tries++
+		if tries < 3 {
+			return RetryAfter(errTest, 100*time.Millisecond)
+		}
+		return errTest
+	})
+	elapsed := time.Since(start)
+	if tries != 3 {
+		t.Errorf("expected 3 tries, got %d", tries)
+	}
+	if !errors.Is(err, errTest) {
+		t.Errorf("expected err to wrap errTest, got: %v", err)
+	}
+	if elapsed < 200*time.Millisecond {
+		t.Errorf("expected RetryAfter delay to be honored, only waited %v", elapsed)
+	}
+}
+
+func TestRetryAfterDoesNotShortenComputedBackoff(t *testing.T) {
+	tries := 0
+	clock := newFakeClock()
+	start := clock.Now()
+	retrier := NewRetrier(2, 50*time.Millisecond, 50*time.Millisecond).WithClock(clock)
+	err := retrier.RunContext(context.Background(), func(ctx context.Context) error {
+		tries++
+		return RetryAfter(errTest, time.Microsecond)
+	})
+	if tries != 2 {
+		t.Errorf("expected 2 tries, got %d", tries)
+	}
+	if !errors.Is(err, errTest) {
+		t.Errorf("expected err to wrap errTest, got: %v", err)
+	}
+	if elapsed := clock.Now().Sub(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected the computed backoff to be kept over a shorter RetryAfter, only waited %v", elapsed)
+	}
+}
+
+func TestParseRetryAfterDeltaSeconds(t *testing.T) {
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"120"}}}
+	d, ok := ParseRetryAfter(resp)
+	if !ok {
+		t.Fatal("expected ok to be true")
+	}
+	if d != 120*time.Second {
+		t.Errorf("expected 120s, got %v", d)
+	}
+}
+
+func TestParseRetryAfterHTTPDate(t *testing.T) {
+	future := time.Now().Add(time.Hour).UTC()
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{future.Format(http.TimeFormat)}}}
+	d, ok := ParseRetryAfter(resp)
+	if !ok {
+		t.Fatal("expected ok to be true")
+	}
+	if d <= 0 || d > time.Hour {
+		t.Errorf("expected a delay close to 1h, got %v", d)
+	}
+}
+
+func TestParseRetryAfterMissingOrInvalid(t *testing.T) {
+	if _, ok := ParseRetryAfter(&http.Response{Header: http.Header{}}); ok {
+		t.Error("expected ok to be false when header is absent")
+	}
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"not-a-valid-value"}}}
+	if _, ok := ParseRetryAfter(resp); ok {
+		t.Error("expected ok to be false for an unparseable value")
+	}
+}
+
+func TestParseRetryAfterFromRealResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	d, ok := ParseRetryAfter(resp)
+	if !ok || d != 5*time.Second {
+		t.Errorf("expected 5s and ok=true, got %v, %v", d, ok)
+	}
+}
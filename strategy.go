@@ -0,0 +1,283 @@
+package retry
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Strategy computes how long to wait before the next retry attempt. attempt
+// is the number of attempts that have already been made (1 for the delay
+// before the second attempt, 2 for the delay before the third, and so on).
+//
+// Reset is called once at the start of every Run/RunContext so that
+// stateful strategies (such as DecorrelatedJitter) start each run from a
+// clean slate.
+type Strategy interface {
+	NextDelay(attempt int) time.Duration
+	Reset()
+}
+
+// RandSourceSetter can optionally be implemented by a Strategy to accept a
+// custom math/rand.Source for its jitter, in place of the global one.
+// WithRandSource uses this to make a Retrier's jitter reproducible.
+type RandSourceSetter interface {
+	SetRandSource(src rand.Source)
+}
+
+// WithRandSource installs src as the source of randomness for the current
+// Strategy's jitter, if it implements RandSourceSetter, making the jitter
+// reproducible (useful in tests). It returns r so calls can be chained onto
+// NewRetrier/NewRetrierWithStrategy; call it after choosing a Strategy, since
+// it wires src into that Strategy immediately rather than on every Run.
+//
+// A Retrier is normally safe to use concurrently, but src itself usually
+// isn't: math/rand.Source and the *rand.Rand built from it aren't safe for
+// concurrent use. Once WithRandSource has been called, only use that Retrier
+// from one goroutine at a time, unless src is backed by a source that's
+// explicitly safe for concurrent use.
+func (r *Retrier) WithRandSource(src rand.Source) *Retrier {
+	if rs, ok := r.strategy.(RandSourceSetter); ok {
+		rs.SetRandSource(src)
+	}
+	return r
+}
+
+// ConstantBackoff waits the same, fixed delay between every attempt.
+type ConstantBackoff struct {
+	Delay time.Duration
+}
+
+// NewConstantBackoff returns a Strategy that waits delay between every
+// attempt.
+func NewConstantBackoff(delay time.Duration) *ConstantBackoff {
+	return &ConstantBackoff{Delay: delay}
+}
+
+// NextDelay implements Strategy.
+func (c *ConstantBackoff) NextDelay(_ int) time.Duration {
+	return c.Delay
+}
+
+// Reset implements Strategy. ConstantBackoff holds no state.
+func (c *ConstantBackoff) Reset() {}
+
+// LinearBackoff waits an increasingly long delay between attempts, growing
+// by Initial every attempt and leveling off at Max.
+type LinearBackoff struct {
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// NewLinearBackoff returns a Strategy that waits attempt*initial between
+// attempts, capped at max.
+func NewLinearBackoff(initial, max time.Duration) *LinearBackoff {
+	return &LinearBackoff{Initial: initial, Max: max}
+}
+
+// NextDelay implements Strategy.
+func (l *LinearBackoff) NextDelay(attempt int) time.Duration {
+	delay := l.Initial * time.Duration(attempt)
+	if delay <= 0 || delay > l.Max {
+		return l.Max
+	}
+	return delay
+}
+
+// Reset implements Strategy. LinearBackoff holds no state.
+func (l *LinearBackoff) Reset() {}
+
+// ExponentialBackoff doubles the delay on every attempt, starting from
+// InitialDelay, with jitter added so that many callers retrying at once
+// don't all wake up at the same instant. Once the doubled delay passes
+// MaxDelay, a jittered value between MaxDelay/2 and MaxDelay is used
+// instead. InitialDelay is added to every delay, and the jitter can take a
+// delay past MaxDelay, so MaxDelay is not a hard upper bound. This is the
+// strategy NewRetrier uses by default.
+type ExponentialBackoff struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	rnd          *rand.Rand
+}
+
+// NewExponentialBackoff returns the classic jittered, doubling backoff
+// strategy. If either value is <= 0, it is set to its respective default.
+func NewExponentialBackoff(initialDelay, maxDelay time.Duration) *ExponentialBackoff {
+	if initialDelay <= 0 {
+		initialDelay = DefaultInitialDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxDelay
+	}
+	return &ExponentialBackoff{InitialDelay: initialDelay, MaxDelay: maxDelay}
+}
+
+// maxShift is the largest shift we'll ever apply to a base delay, however
+// many attempts are made. With it, InitialDelay<<shift stays within an int64
+// for any InitialDelay below 2^31ns (about 2.1s); a larger one can wrap, and
+// a wrapped result that is <= 0 is treated like one past MaxDelay.
+const maxShift = 32
+
+// NextDelay implements Strategy.
+func (e *ExponentialBackoff) NextDelay(attempt int) time.Duration {
+	shift := attempt
+	if shift > maxShift {
+		shift = maxShift
+	}
+	backoff := e.InitialDelay << uint(shift)
+	if backoff <= 0 || backoff > e.MaxDelay {
+		backoff = jitterDuration(e.rnd, e.MaxDelay/2)
+	} else {
+		backoff = jitterDuration(e.rnd, backoff)
+	}
+	return backoff + e.InitialDelay
+}
+
+// Reset implements Strategy. ExponentialBackoff holds no other state.
+func (e *ExponentialBackoff) Reset() {}
+
+// SetRandSource implements RandSourceSetter.
+func (e *ExponentialBackoff) SetRandSource(src rand.Source) {
+	e.rnd = rand.New(src)
+}
+
+// FullJitter waits a random duration between 0 and the capped exponential
+// backoff for the attempt, as described in the AWS Architecture Blog post
+// "Exponential Backoff And Jitter".
+type FullJitter struct {
+	Base time.Duration
+	Cap  time.Duration
+	rnd  *rand.Rand
+}
+
+// NewFullJitter returns a Strategy that sleeps rand(0, min(cap, base*2^n)).
+func NewFullJitter(base, cap time.Duration) *FullJitter {
+	return &FullJitter{Base: base, Cap: cap}
+}
+
+// NextDelay implements Strategy.
+func (f *FullJitter) NextDelay(attempt int) time.Duration {
+	temp := expCap(f.Base, f.Cap, attempt)
+	if temp <= 0 {
+		return 0
+	}
+	return time.Duration(randInt63n(f.rnd, int64(temp)))
+}
+
+// Reset implements Strategy. FullJitter holds no other state.
+func (f *FullJitter) Reset() {}
+
+// SetRandSource implements RandSourceSetter.
+func (f *FullJitter) SetRandSource(src rand.Source) {
+	f.rnd = rand.New(src)
+}
+
+// EqualJitter always waits at least half of the capped exponential backoff,
+// then adds a random amount up to the other half, so the delay never drops
+// all the way to zero the way FullJitter's can.
+type EqualJitter struct {
+	Base time.Duration
+	Cap  time.Duration
+	rnd  *rand.Rand
+}
+
+// NewEqualJitter returns a Strategy that sleeps temp/2 + rand(0, temp/2)
+// where temp = min(cap, base*2^n).
+func NewEqualJitter(base, cap time.Duration) *EqualJitter {
+	return &EqualJitter{Base: base, Cap: cap}
+}
+
+// NextDelay implements Strategy.
+func (e *EqualJitter) NextDelay(attempt int) time.Duration {
+	temp := expCap(e.Base, e.Cap, attempt)
+	half := temp / 2
+	if half <= 0 {
+		return half
+	}
+	return half + time.Duration(randInt63n(e.rnd, int64(half)))
+}
+
+// Reset implements Strategy. EqualJitter holds no other state.
+func (e *EqualJitter) Reset() {}
+
+// SetRandSource implements RandSourceSetter.
+func (e *EqualJitter) SetRandSource(src rand.Source) {
+	e.rnd = rand.New(src)
+}
+
+// DecorrelatedJitter bases each delay on the previous one rather than on the
+// attempt number, which spreads out retries from competing callers even
+// faster than FullJitter or EqualJitter.
+//
+// It carries state (the previous delay) across calls to NextDelay, so a
+// single DecorrelatedJitter must not be shared between concurrently running
+// Retrier.Run calls: construct one per Retrier, or per Run, rather than
+// reusing one across goroutines.
+type DecorrelatedJitter struct {
+	Base time.Duration
+	Cap  time.Duration
+	prev time.Duration
+	rnd  *rand.Rand
+}
+
+// NewDecorrelatedJitter returns a Strategy that sleeps
+// min(cap, rand(base, prev*3)).
+func NewDecorrelatedJitter(base, cap time.Duration) *DecorrelatedJitter {
+	return &DecorrelatedJitter{Base: base, Cap: cap}
+}
+
+// NextDelay implements Strategy.
+func (d *DecorrelatedJitter) NextDelay(_ int) time.Duration {
+	prev := d.prev
+	if prev <= 0 {
+		prev = d.Base
+	}
+	top := prev * 3
+	delay := d.Base + time.Duration(randInt63n(d.rnd, int64(top-d.Base)+1))
+	if delay > d.Cap {
+		delay = d.Cap
+	}
+	d.prev = delay
+	return delay
+}
+
+// Reset implements Strategy, clearing the previous delay so the next Run
+// starts over from Base.
+func (d *DecorrelatedJitter) Reset() {
+	d.prev = 0
+}
+
+// SetRandSource implements RandSourceSetter.
+func (d *DecorrelatedJitter) SetRandSource(src rand.Source) {
+	d.rnd = rand.New(src)
+}
+
+// expCap returns min(cap, base*2^attempt), guarding against the shift
+// overflowing into a negative duration the same way ExponentialBackoff does.
+func expCap(base, cap time.Duration, attempt int) time.Duration {
+	shift := attempt
+	if shift > maxShift {
+		shift = maxShift
+	}
+	temp := base << uint(shift)
+	if temp <= 0 || temp > cap {
+		temp = cap
+	}
+	return temp
+}
+
+// jitterDuration returns a value in [duration, 2*duration).
+func jitterDuration(rnd *rand.Rand, duration time.Duration) time.Duration {
+	if duration <= 0 {
+		return duration
+	}
+	return time.Duration(randInt63n(rnd, int64(duration))) + duration
+}
+
+// randInt63n draws from rnd if non-nil, falling back to the global,
+// concurrency-safe math/rand source otherwise.
+func randInt63n(rnd *rand.Rand, n int64) int64 {
+	if rnd != nil {
+		return rnd.Int63n(n)
+	}
+	return rand.Int63n(n)
+}
@@ -0,0 +1,103 @@
+package retry
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestConstantBackoffAlwaysReturnsDelay(t *testing.T) {
+	s := NewConstantBackoff(100 * time.Millisecond)
+	for attempt := 0; attempt < 10; attempt++ {
+		if d := s.NextDelay(attempt); d != 100*time.Millisecond {
+			t.Errorf("attempt %d: expected 100ms, got %v", attempt, d)
+		}
+	}
+}
+
+func TestLinearBackoffGrowsThenCaps(t *testing.T) {
+	s := NewLinearBackoff(10*time.Millisecond, 25*time.Millisecond)
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 10 * time.Millisecond},
+		{2, 20 * time.Millisecond},
+		{3, 25 * time.Millisecond},
+		{100, 25 * time.Millisecond},
+	}
+	for _, c := range cases {
+		if d := s.NextDelay(c.attempt); d != c.want {
+			t.Errorf("attempt %d: expected %v, got %v", c.attempt, c.want, d)
+		}
+	}
+}
+
+func TestJitterStrategiesStayWithinBounds(t *testing.T) {
+	base := 10 * time.Millisecond
+	cap := 100 * time.Millisecond
+	strategies := map[string]Strategy{
+		"FullJitter":         NewFullJitter(base, cap),
+		"EqualJitter":        NewEqualJitter(base, cap),
+		"DecorrelatedJitter": NewDecorrelatedJitter(base, cap),
+	}
+	for name, s := range strategies {
+		t.Run(name, func(t *testing.T) {
+			s.Reset()
+			for attempt := 1; attempt < 50; attempt++ {
+				d := s.NextDelay(attempt)
+				if d < 0 || d > cap {
+					t.Errorf("attempt %d: delay %v out of bounds [0, %v]", attempt, d, cap)
+				}
+			}
+		})
+	}
+}
+
+func TestExponentialBackoffNeverPanicsOrGoesNegative(t *testing.T) {
+	s := NewExponentialBackoff(500*time.Millisecond, 1*time.Millisecond)
+	for attempt := 0; attempt < 1000; attempt++ {
+		if d := s.NextDelay(attempt); d < 0 {
+			t.Errorf("attempt %d: expected non-negative delay, got %v", attempt, d)
+		}
+	}
+}
+
+func TestWithRandSourceMakesJitterReproducible(t *testing.T) {
+	schedule := func() []time.Duration {
+		strategy := NewFullJitter(10*time.Millisecond, 100*time.Millisecond)
+		retrier := NewRetrierWithStrategy(6, strategy).
+			WithClock(newFakeClock()).
+			WithRandSource(rand.NewSource(42))
+		var delays []time.Duration
+		_ = retrier.Run(func() error {
+			delays = append(delays, strategy.NextDelay(len(delays)))
+			return errTest
+		})
+		return delays
+	}
+	gotA, gotB := schedule(), schedule()
+	if len(gotA) != len(gotB) {
+		t.Fatalf("expected matching number of delays, got %d and %d", len(gotA), len(gotB))
+	}
+	for i := range gotA {
+		if gotA[i] != gotB[i] {
+			t.Errorf("delay %d: expected reproducible jitter, got %v and %v", i, gotA[i], gotB[i])
+		}
+	}
+}
+
+func TestNewRetrierWithStrategyUsesGivenStrategy(t *testing.T) {
+	tries := 0
+	retrier := NewRetrierWithStrategy(3, NewConstantBackoff(time.Millisecond))
+	err := retrier.Run(func() error {
+		tries++
+		return errTest
+	})
+	if tries != 3 {
+		t.Errorf("expected 3 tries, got %d", tries)
+	}
+	if err != errTest {
+		t.Errorf("err should equal errTest, got: %v", err)
+	}
+}